@@ -2,26 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
-
+	"time"
+
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/config"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/dag"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/deploy"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/drift"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/microstacks"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/refs"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/statelock"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
-	"gopkg.in/yaml.v3"
 )
 
-// Config represents the infrastructure configuration
-type Config struct {
-	Environment string `yaml:"environment"`
-	Customer    string `yaml:"customer"`
-	// Add other fields as needed
-}
-
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
@@ -33,6 +35,31 @@ func main() {
 	switch stage {
 	case "init":
 		handleInit()
+	case "unlock":
+		if len(os.Args) < 3 {
+			log.Fatalf("Usage: cmd-deploy unlock <stack-name> --config <file>")
+		}
+		stackName := os.Args[2]
+		configFile := getConfigFile()
+		handleUnlock(stackName, configFile)
+	case "drift":
+		if len(os.Args) < 3 {
+			log.Fatalf("Usage: cmd-deploy drift {vpc|core|apps} --config <file> [--watch <duration>]")
+		}
+		driftStage := os.Args[2]
+		configFile := getConfigFile()
+		handleDrift(driftStage, configFile, watchIntervalFromArgs())
+	case "config":
+		if len(os.Args) < 3 || os.Args[2] != "render" {
+			log.Fatalf("Usage: cmd-deploy config render --config <file> --microstack <name>")
+		}
+		configFile := getConfigFile()
+		handleConfigRender(configFile, getMicrostackFlag())
+	case "graph":
+		if len(os.Args) < 3 {
+			log.Fatalf("Usage: cmd-deploy graph --stage {vpc|core|apps} [--format dot]")
+		}
+		handleGraph(getStageFlag(), getFormatFlag())
 	case "vpc", "core", "apps":
 		if len(os.Args) < 3 {
 			log.Fatalf("Usage: cmd-deploy %s <pulumi-action> --config <file>", stage)
@@ -48,12 +75,21 @@ func main() {
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  cmd-deploy init --config <file>")
-	fmt.Println("  cmd-deploy {vpc|core|apps} {up|down|preview} --config <file>")
+	fmt.Println("  cmd-deploy {vpc|core|apps} {up|down|preview} --config <file> [--parallelism N] [--json]")
+	fmt.Println("  cmd-deploy drift {vpc|core|apps} --config <file> [--watch <duration>]")
+	fmt.Println("  cmd-deploy config render --config <file> --microstack <name> [--set a.b.c=value ...]")
+	fmt.Println("  cmd-deploy graph --stage {vpc|core|apps} [--format dot]")
+	fmt.Println("  cmd-deploy unlock <stack-name> --config <file>")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  cmd-deploy init --config configs/sample-config.yaml")
 	fmt.Println("  cmd-deploy vpc up --config configs/sample-config.yaml")
-	fmt.Println("  cmd-deploy core preview --config configs/sample-config.yaml")
+	fmt.Println("  cmd-deploy core preview --config configs/sample-config.yaml --parallelism 3")
+	fmt.Println("  cmd-deploy vpc up --config configs/sample-config.yaml --json | jq .")
+	fmt.Println("  cmd-deploy drift core --config configs/sample-config.yaml --watch 15m")
+	fmt.Println("  cmd-deploy config render --config configs/sample-config.yaml --microstack eks")
+	fmt.Println("  cmd-deploy graph --stage apps --format dot")
+	fmt.Println("  cmd-deploy unlock acme-core-rds-us-east-1 --config configs/sample-config.yaml")
 }
 
 func getConfigFile() string {
@@ -66,12 +102,70 @@ func getConfigFile() string {
 	return ""
 }
 
+func getMicrostackFlag() string {
+	for i, arg := range os.Args {
+		if arg == "--microstack" && i+1 < len(os.Args) {
+			return os.Args[i+1]
+		}
+	}
+	log.Fatal("--microstack flag is required")
+	return ""
+}
+
+func getStageFlag() string {
+	for i, arg := range os.Args {
+		if arg == "--stage" && i+1 < len(os.Args) {
+			return os.Args[i+1]
+		}
+	}
+	log.Fatal("--stage flag is required")
+	return ""
+}
+
+// getFormatFlag reads --format from os.Args, defaulting to "dot" since it's
+// currently the only format `cmd-deploy graph` supports.
+func getFormatFlag() string {
+	for i, arg := range os.Args {
+		if arg == "--format" && i+1 < len(os.Args) {
+			return os.Args[i+1]
+		}
+	}
+	return "dot"
+}
+
+// jsonFlagSet reports whether --json was passed on os.Args, so `up`/`down`/
+// `preview` can stream machine-readable engine events instead of
+// human-readable progress text for scripted callers.
+func jsonFlagSet() bool {
+	for _, arg := range os.Args {
+		if arg == "--json" {
+			return true
+		}
+	}
+	return false
+}
+
+// parallelismFromArgs reads --parallelism <n> from os.Args. 0 (the default)
+// means dag.Run should use as much concurrency as the stage's DAG allows.
+func parallelismFromArgs() int {
+	for i, arg := range os.Args {
+		if arg == "--parallelism" && i+1 < len(os.Args) {
+			n, err := strconv.Atoi(os.Args[i+1])
+			if err != nil {
+				log.Fatalf("Invalid --parallelism value %q: %v", os.Args[i+1], err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
 func handleInit() {
 	fmt.Println("Initializing infrastructure...")
 
 	// Load config to get bucket name
 	configFile := getConfigFile()
-	config, err := loadConfig(configFile)
+	cfg, err := config.Load(configFile, setFlagsFromArgs())
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -81,30 +175,30 @@ func handleInit() {
 		log.Fatal("AWS_REGION environment variable must be set")
 	}
 
-	bucketName := fmt.Sprintf("pulumi-state-%s-%s", config.Environment, config.Customer)
+	bucketName := fmt.Sprintf("pulumi-state-%s-%s", cfg.Environment, cfg.Customer)
 
 	fmt.Printf("Creating S3 state bucket: %s\n", bucketName)
 	if err := createS3Bucket(bucketName, region); err != nil {
 		log.Fatalf("Failed to create S3 bucket: %v", err)
 	}
 
-	fmt.Printf("Configuring Pulumi backend: s3://%s\n", bucketName)
-	if err := runCommand("pulumi", "login", fmt.Sprintf("s3://%s", bucketName)); err != nil {
-		log.Fatalf("Failed to configure Pulumi backend: %v", err)
+	ctx := context.Background()
+	lockTableName := statelock.TableName(cfg.Environment, cfg.Customer)
+	fmt.Printf("Creating DynamoDB lock table: %s\n", lockTableName)
+	if err := statelock.CreateTable(ctx, lockTableName, region); err != nil {
+		log.Fatalf("Failed to create lock table: %v", err)
 	}
 
 	fmt.Println("Creating stacks...")
 	stages := []string{"vpc", "core", "apps"}
 
 	for _, stage := range stages {
-		microstacks := getMicrostacksForStage(stage)
-
-		for _, microstack := range microstacks {
-			stackName := fmt.Sprintf("%s-%s-%s-%s", config.Customer, stage, microstack, region)
+		for _, microstack := range microstacks.ForStage(stage) {
+			stackName := refs.StackName(cfg.Customer, stage, microstack, region)
 			fmt.Printf("Creating stack: %s\n", stackName)
 
-			if err := runCommand("pulumi", "stack", "init", stackName); err != nil {
-				fmt.Printf("Stack %s may already exist, continuing...\n", stackName)
+			if _, err := deploy.New(ctx, cfg, stackName, bucketName, region, microstack); err != nil {
+				log.Fatalf("Failed to create stack %s: %v", stackName, err)
 			}
 		}
 	}
@@ -113,7 +207,7 @@ func handleInit() {
 }
 
 func handleDeployStage(stage, action, configFile string) {
-	config, err := loadConfig(configFile)
+	cfg, err := config.Load(configFile, setFlagsFromArgs())
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -123,87 +217,299 @@ func handleDeployStage(stage, action, configFile string) {
 		log.Fatal("AWS_REGION environment variable must be set")
 	}
 
-	bucketName := fmt.Sprintf("pulumi-state-%s-%s", config.Environment, config.Customer)
+	bucketName := fmt.Sprintf("pulumi-state-%s-%s", cfg.Environment, cfg.Customer)
+	lockTableName := statelock.TableName(cfg.Environment, cfg.Customer)
+	lockTTL := lockTTLFromEnv()
+	parallelism := parallelismFromArgs()
+	jsonOutput := jsonFlagSet()
 
-	// Connect to Pulumi backend
-	fmt.Printf("Logging into S3 backend: s3://%s\n", bucketName)
-	if err := runCommand("pulumi", "login", fmt.Sprintf("s3://%s", bucketName)); err != nil {
-		log.Fatalf("Failed to login to Pulumi backend: %v", err)
+	graph, err := buildGraph(stage)
+	if err != nil {
+		log.Fatalf("Failed to build DAG for stage %s: %v", stage, err)
+	}
+	if action == "down" {
+		graph = graph.Reverse()
 	}
 
-	// Get microstacks for this stage
-	microstacks := getMicrostacksForStage(stage)
+	order, err := graph.TopoOrder()
+	if err != nil {
+		log.Fatalf("Failed to resolve DAG order for stage %s: %v", stage, err)
+	}
 
-	fmt.Printf("Deploying stage: %s with action: %s\n", stage, action)
-	fmt.Printf("Microstacks to process: %s\n", strings.Join(microstacks, ", "))
+	if !jsonOutput {
+		fmt.Printf("Deploying stage: %s with action: %s (parallelism=%d)\n", stage, action, parallelism)
+		fmt.Printf("Microstacks to process: %s\n", strings.Join(order, ", "))
+	}
 
-	// Execute action on each microstack in order
-	for _, microstack := range microstacks {
-		stackName := fmt.Sprintf("%s-%s-%s-%s", config.Customer, stage, microstack, region)
+	ctx := context.Background()
 
-		fmt.Printf("Processing microstack: %s (%s)\n", microstack, stackName)
+	results := dag.Run(ctx, graph, parallelism, func(ctx context.Context, microstack string) (map[string]int, error) {
+		stackName := refs.StackName(cfg.Customer, stage, microstack, region)
+		if !jsonOutput {
+			fmt.Printf("Processing microstack: %s (%s)\n", microstack, stackName)
+		}
 
-		// Select the microstack
-		if err := runCommand("pulumi", "stack", "select", stackName); err != nil {
-			log.Fatalf("Failed to select stack: %v", err)
+		lock, err := statelock.Acquire(ctx, lockTableName, stackName, region, lockTTL)
+		if err != nil {
+			return nil, err
 		}
+		defer func() {
+			if err := lock.Release(ctx); err != nil {
+				log.Printf("Failed to release lock for %s: %v", stackName, err)
+			}
+		}()
 
-		// Pass config to Pulumi program
-		os.Setenv("CONFIG_FILE", configFile)
-		if err := runCommand("pulumi", "config", "set", "microstack", microstack); err != nil {
-			log.Fatalf("Failed to set microstack config: %v", err)
+		d, err := deploy.New(ctx, cfg, stackName, bucketName, region, microstack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare stack %s: %w", stackName, err)
 		}
 
-		// Execute Pulumi action on this microstack
-		var args []string
-		if action == "up" || action == "down" {
-			args = []string{action, "--yes"}
-		} else {
-			args = []string{action}
+		switch action {
+		case "up":
+			result, err := d.Up(ctx, jsonOutput)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run up on %s: %w", stackName, err)
+			}
+			if !jsonOutput {
+				fmt.Printf("✅ %s up complete: %s\n", microstack, result.Summary.Message)
+			}
+			return resourceChanges(result.Summary.ResourceChanges), nil
+		case "down":
+			result, err := d.Destroy(ctx, jsonOutput)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run down on %s: %w", stackName, err)
+			}
+			if !jsonOutput {
+				fmt.Printf("✅ %s down complete: %s\n", microstack, result.Summary.Message)
+			}
+			return resourceChanges(result.Summary.ResourceChanges), nil
+		case "preview":
+			if _, err := d.Preview(ctx, jsonOutput); err != nil {
+				return nil, fmt.Errorf("failed to run preview on %s: %w", stackName, err)
+			}
+			if !jsonOutput {
+				fmt.Printf("✅ %s preview complete\n", microstack)
+			}
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown pulumi action: %s", action)
 		}
+	})
+
+	if !jsonOutput {
+		printStageSummary(stage, action, results)
+	}
 
-		if err := runCommand("pulumi", args...); err != nil {
-			log.Fatalf("Failed to run pulumi %s: %v", action, err)
+	for _, r := range results {
+		if r.Status == dag.StatusFailed {
+			os.Exit(1)
 		}
+	}
+}
 
-		fmt.Printf("✅ Completed %s %s\n", microstack, action)
+// buildGraph assembles stage's microstacks and their declared intra-stage
+// dependencies into a dag.Graph.
+func buildGraph(stage string) (*dag.Graph, error) {
+	microstacksInStage := microstacks.ForStage(stage)
+	nodes := make([]dag.Node, 0, len(microstacksInStage))
+	for _, m := range microstacksInStage {
+		nodes = append(nodes, dag.Node{Name: m, DependsOn: microstacks.DependsOn(m)})
 	}
+	return dag.New(nodes)
+}
 
-	fmt.Printf("✅ Successfully completed stage %s %s\n", stage, action)
+// resourceChanges unwraps the pointer-to-map shape Automation API results
+// carry their per-op-type resource-change counts in.
+func resourceChanges(changes *map[string]int) map[string]int {
+	if changes == nil {
+		return map[string]int{}
+	}
+	return *changes
 }
 
-func getMicrostacksForStage(stage string) []string {
-	switch stage {
-	case "vpc":
-		return []string{"networking", "acls"}
-	case "core":
-		return []string{"s3", "route53", "rds", "eks", "opensearch", "cloudfront", "certificates"}
-	case "apps":
-		return []string{"eks-addons", "helm-charts", "storage-classes", "ingress-classes"}
+// printStageSummary prints each microstack's terminal status, duration, and
+// resource-change counts once a stage's DAG has finished running.
+func printStageSummary(stage, action string, results []dag.Result) {
+	fmt.Printf("\nSummary for stage %s %s:\n", stage, action)
+	for _, r := range results {
+		switch r.Status {
+		case dag.StatusSucceeded:
+			fmt.Printf("  %-20s %-10s %-10s %v\n", r.Name, r.Status, r.Duration.Round(time.Millisecond), r.ResourceChanges)
+		case dag.StatusFailed:
+			fmt.Printf("  %-20s %-10s %-10s error: %v\n", r.Name, r.Status, r.Duration.Round(time.Millisecond), r.Err)
+		case dag.StatusCanceled:
+			fmt.Printf("  %-20s %-10s\n", r.Name, r.Status)
+		}
+	}
+}
+
+// handleGraph prints stage's resolved microstack DAG so an operator can
+// inspect execution order and dependencies before running `up`/`down`.
+func handleGraph(stage, format string) {
+	graph, err := buildGraph(stage)
+	if err != nil {
+		log.Fatalf("Failed to build DAG for stage %s: %v", stage, err)
+	}
+
+	switch format {
+	case "dot":
+		fmt.Print(graph.DOT(stage))
 	default:
-		return []string{}
+		log.Fatalf("Unsupported --format %q (supported: dot)", format)
 	}
 }
 
-func loadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
+// handleConfigRender prints the effective, resolved config one microstack
+// will see once config.Load has merged defaults, environment/customer
+// overlays, configFile, and any --set flags.
+func handleConfigRender(configFile, microstack string) {
+	cfg, err := config.Load(configFile, setFlagsFromArgs())
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	resolved, err := cfg.Microstack(microstack)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		log.Fatalf("%v", err)
+	}
+
+	body, err := json.MarshalIndent(resolved, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal resolved config: %v", err)
+	}
+	fmt.Println(string(body))
+}
+
+func handleUnlock(stackName, configFile string) {
+	cfg, err := config.Load(configFile, setFlagsFromArgs())
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		log.Fatal("AWS_REGION environment variable must be set")
+	}
+
+	lockTableName := statelock.TableName(cfg.Environment, cfg.Customer)
+	if err := statelock.Unlock(context.Background(), lockTableName, stackName, region); err != nil {
+		log.Fatalf("Failed to unlock %s: %v", stackName, err)
 	}
 
-	return &config, nil
+	fmt.Printf("✅ Released lock on %s\n", stackName)
+}
+
+func handleDrift(stage, configFile string, watchInterval time.Duration) {
+	cfg, err := config.Load(configFile, setFlagsFromArgs())
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		log.Fatal("AWS_REGION environment variable must be set")
+	}
+
+	bucketName := fmt.Sprintf("pulumi-state-%s-%s", cfg.Environment, cfg.Customer)
+	checker := drift.NewChecker(cfg, bucketName, region, driftNotifiersFromEnv()...)
+	ctx := context.Background()
+
+	for {
+		report, err := checker.Run(ctx, stage)
+		if err != nil {
+			log.Fatalf("Failed to run drift check on stage %s: %v", stage, err)
+		}
+
+		body, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal drift report: %v", err)
+		}
+		fmt.Println(string(body))
+
+		if watchInterval <= 0 {
+			return
+		}
+
+		sleep := watchInterval + jitter(watchInterval)
+		fmt.Printf("Next drift check for stage %s in %s\n", stage, sleep)
+		time.Sleep(sleep)
+	}
+}
+
+// jitter returns a random duration up to 10% of interval, so concurrent
+// `--watch` runs across stages don't all hammer AWS in lockstep.
+func jitter(interval time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(interval)/10 + 1))
+}
+
+// watchIntervalFromArgs reads --watch <duration> from os.Args, returning 0
+// (run once) when it isn't set.
+func watchIntervalFromArgs() time.Duration {
+	for i, arg := range os.Args {
+		if arg == "--watch" && i+1 < len(os.Args) {
+			d, err := time.ParseDuration(os.Args[i+1])
+			if err != nil {
+				log.Fatalf("Invalid --watch duration %q: %v", os.Args[i+1], err)
+			}
+			return d
+		}
+	}
+	return 0
+}
+
+// driftNotifiersFromEnv builds the Notifier set for drift reports from
+// environment variables, so `cmd-deploy drift` stays a single binary with no
+// extra config file plumbing: DRIFT_SNS_TOPIC_ARN and DRIFT_SLACK_WEBHOOK_URL
+// are both optional and additive to the always-on stdout notifier.
+func driftNotifiersFromEnv() []drift.Notifier {
+	notifiers := []drift.Notifier{drift.StdoutNotifier{}}
+
+	if topicARN := os.Getenv("DRIFT_SNS_TOPIC_ARN"); topicARN != "" {
+		notifiers = append(notifiers, drift.SNSNotifier{
+			TopicARN: topicARN,
+			Region:   os.Getenv("AWS_REGION"),
+		})
+	}
+
+	if webhookURL := os.Getenv("DRIFT_SLACK_WEBHOOK_URL"); webhookURL != "" {
+		notifiers = append(notifiers, drift.SlackNotifier{WebhookURL: webhookURL})
+	}
+
+	return notifiers
+}
+
+// lockTTLFromEnv reads STATE_LOCK_TTL (a Go duration string, e.g. "20m") so
+// operators can tune how long a crashed run's lock survives; statelock
+// applies its own default when this returns 0.
+func lockTTLFromEnv() time.Duration {
+	v := os.Getenv("STATE_LOCK_TTL")
+	if v == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid STATE_LOCK_TTL %q: %v", v, err)
+	}
+	return ttl
+}
+
+// setFlagsFromArgs collects every "--set a.b.c=value" flag from os.Args, in
+// the order they appear, for config.Load to apply on top of its other layers.
+func setFlagsFromArgs() []string {
+	var sets []string
+	for i, arg := range os.Args {
+		if arg == "--set" && i+1 < len(os.Args) {
+			sets = append(sets, os.Args[i+1])
+		}
+	}
+	return sets
 }
 
 func createS3Bucket(bucketName, region string) error {
 	ctx := context.TODO()
 
 	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
 	if err != nil {
 		return fmt.Errorf("failed to load AWS config: %w", err)
 	}
@@ -251,10 +557,3 @@ func createS3Bucket(bucketName, region string) error {
 	fmt.Printf("Created S3 bucket: %s\n", bucketName)
 	return nil
 }
-
-func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
@@ -3,19 +3,17 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/config"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/microstacks"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
-	"gopkg.in/yaml.v3"
+	pulumiconfig "github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
-// Config represents the infrastructure configuration
-type Config struct {
-	Environment string `yaml:"environment"`
-	Customer    string `yaml:"customer"`
-	// Add other fields as needed
-}
-
+// main is kept as a standalone Pulumi program entrypoint for `pulumi up`
+// invoked directly against this directory. cmd-deploy no longer shells out
+// to it; it registers microstacks.RunFunc with the Automation API instead.
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
 		// Get config file from environment variable (set by CLI)
@@ -24,200 +22,35 @@ func main() {
 			return fmt.Errorf("CONFIG_FILE environment variable must be set")
 		}
 
-		// Load configuration
-		cfg, err := loadConfig(configFile)
+		// Load the layered configuration (defaults, environment/customer
+		// overlays, the config file, and any CONFIG_SET overrides).
+		cfg, err := config.Load(configFile, configSetFromEnv())
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		// Get microstack from Pulumi config (set by CLI)
-		pulumiCfg := config.New(ctx, "")
+		pulumiCfg := pulumiconfig.New(ctx, "")
 		microstack := pulumiCfg.Get("microstack")
 		if microstack == "" {
 			return fmt.Errorf("microstack must be set in Pulumi config")
 		}
 
-		// Determine stage from microstack name
-		stage := getStageFromMicrostack(microstack)
-
-		ctx.Log.Info(fmt.Sprintf("Deploying %s microstack in %s stage for customer: %s", microstack, stage, cfg.Customer), nil)
-
-		// Deploy based on stage
-		switch stage {
-		case "vpc":
-			return deployVPCStage(ctx, cfg, microstack)
-		case "core":
-			return deployCoreStage(ctx, cfg, microstack)
-		case "apps":
-			return deployAppsStage(ctx, cfg, microstack)
-		default:
-			return fmt.Errorf("unknown stage for microstack: %s", microstack)
+		run, err := microstacks.RunFunc(cfg, microstack)
+		if err != nil {
+			return err
 		}
+		return run(ctx)
 	})
 }
 
-func loadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	return &config, nil
-}
-
-func getStageFromMicrostack(microstack string) string {
-	vpcMicrostacks := []string{"networking", "acls"}
-	coreMicrostacks := []string{"s3", "route53", "rds", "eks", "opensearch", "cloudfront", "certificates"}
-	appsMicrostacks := []string{"eks-addons", "helm-charts", "storage-classes", "ingress-classes"}
-
-	for _, ms := range vpcMicrostacks {
-		if ms == microstack {
-			return "vpc"
-		}
-	}
-	for _, ms := range coreMicrostacks {
-		if ms == microstack {
-			return "core"
-		}
-	}
-	for _, ms := range appsMicrostacks {
-		if ms == microstack {
-			return "apps"
-		}
-	}
-	return "unknown"
-}
-
-// Stage deployment functions
-func deployVPCStage(ctx *pulumi.Context, cfg *Config, microstack string) error {
-	switch microstack {
-	case "networking":
-		return deployNetworking(ctx, cfg)
-	case "acls":
-		return deployACLs(ctx, cfg)
-	default:
-		return fmt.Errorf("unknown VPC microstack: %s", microstack)
-	}
-}
-
-func deployCoreStage(ctx *pulumi.Context, cfg *Config, microstack string) error {
-	switch microstack {
-	case "s3":
-		return deployS3(ctx, cfg)
-	case "route53":
-		return deployRoute53(ctx, cfg)
-	case "rds":
-		return deployRDS(ctx, cfg)
-	case "eks":
-		return deployEKS(ctx, cfg)
-	case "opensearch":
-		return deployOpenSearch(ctx, cfg)
-	case "cloudfront":
-		return deployCloudFront(ctx, cfg)
-	case "certificates":
-		return deployCertificates(ctx, cfg)
-	default:
-		return fmt.Errorf("unknown Core microstack: %s", microstack)
-	}
-}
-
-func deployAppsStage(ctx *pulumi.Context, cfg *Config, microstack string) error {
-	switch microstack {
-	case "eks-addons":
-		return deployEKSAddons(ctx, cfg)
-	case "helm-charts":
-		return deployHelmCharts(ctx, cfg)
-	case "storage-classes":
-		return deployStorageClasses(ctx, cfg)
-	case "ingress-classes":
-		return deployIngressClasses(ctx, cfg)
-	default:
-		return fmt.Errorf("unknown Apps microstack: %s", microstack)
+// configSetFromEnv reads CONFIG_SET (comma-separated "a.b.c=value" entries,
+// the env-var equivalent of cmd-deploy's --set flag) so a direct `pulumi up`
+// against this program can apply the same overrides the CLI does.
+func configSetFromEnv() []string {
+	raw := os.Getenv("CONFIG_SET")
+	if raw == "" {
+		return nil
 	}
-}
-
-// VPC Stage Functions
-func deployNetworking(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Networking microstack", nil)
-	// TODO: Implement VPC, subnets, NAT gateways, etc.
-	return nil
-}
-
-func deployACLs(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying ACLs microstack", nil)
-	// TODO: Implement Network ACLs and security groups
-	return nil
-}
-
-// Core Stage Functions
-func deployS3(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying S3 microstack", nil)
-	// TODO: Implement S3 buckets and policies
-	return nil
-}
-
-func deployRoute53(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Route53 microstack", nil)
-	// TODO: Implement DNS and hosted zones
-	return nil
-}
-
-func deployRDS(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying RDS microstack", nil)
-	// TODO: Implement database instances
-	return nil
-}
-
-func deployEKS(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying EKS microstack", nil)
-	// TODO: Implement Kubernetes cluster
-	return nil
-}
-
-func deployOpenSearch(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying OpenSearch microstack", nil)
-	// TODO: Implement search and analytics
-	return nil
-}
-
-func deployCloudFront(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying CloudFront microstack", nil)
-	// TODO: Implement CDN and distributions
-	return nil
-}
-
-func deployCertificates(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Certificates microstack", nil)
-	// TODO: Implement SSL/TLS certificates
-	return nil
-}
-
-// Apps Stage Functions
-func deployEKSAddons(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying EKS Addons microstack", nil)
-	// TODO: Implement External-DNS, cert-manager, etc.
-	return nil
-}
-
-func deployHelmCharts(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Helm Charts microstack", nil)
-	// TODO: Implement application deployments
-	return nil
-}
-
-func deployStorageClasses(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Storage Classes microstack", nil)
-	// TODO: Implement Kubernetes storage configurations
-	return nil
-}
-
-func deployIngressClasses(ctx *pulumi.Context, cfg *Config) error {
-	ctx.Log.Info("Deploying Ingress Classes microstack", nil)
-	// TODO: Implement ingress controllers and configurations
-	return nil
+	return strings.Split(raw, ",")
 }
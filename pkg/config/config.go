@@ -0,0 +1,63 @@
+// Package config loads the infrastructure configuration every microstack
+// deploy function and the cmd-deploy CLI share. Config is assembled in
+// layers — a base defaults.yaml shipped with the repo, an environment
+// overlay, a customer overlay, the instance file passed via --config, and
+// any --set overrides — merged deep-right-wins, then validated against a
+// schema generated from the struct tags below. See Load.
+package config
+
+// Config is the fully-resolved, effective configuration for one deploy
+// invocation.
+type Config struct {
+	Environment string `yaml:"environment" json:"environment" validate:"required"`
+	Customer    string `yaml:"customer" json:"customer" validate:"required"`
+
+	// DriftSeverity maps a microstack name to "critical" or "warning" for
+	// pkg/drift; microstacks left unlisted default to "warning".
+	DriftSeverity map[string]string `yaml:"driftSeverity" json:"driftSeverity,omitempty"`
+
+	// Microstacks carries the per-microstack knobs that deploy functions
+	// read out of, grouped by microstack name.
+	Microstacks MicrostacksConfig `yaml:"microstacks" json:"microstacks,omitempty"`
+}
+
+// MicrostacksConfig groups the per-microstack config structs under the
+// microstack name they belong to. A nil field means no layer Load merged,
+// the embedded defaults included, set anything for that microstack.
+type MicrostacksConfig struct {
+	Networking   *NetworkingConfig   `yaml:"networking,omitempty" json:"networking,omitempty"`
+	EKS          *EKSConfig          `yaml:"eks,omitempty" json:"eks,omitempty"`
+	RDS          *RDSConfig          `yaml:"rds,omitempty" json:"rds,omitempty"`
+	Route53      *Route53Config      `yaml:"route53,omitempty" json:"route53,omitempty"`
+	Certificates *CertificatesConfig `yaml:"certificates,omitempty" json:"certificates,omitempty"`
+}
+
+// NetworkingConfig is the networking microstack's per-deployment knobs.
+type NetworkingConfig struct {
+	VpcCIDR           string   `yaml:"vpcCidr" json:"vpcCidr" validate:"required"`
+	AvailabilityZones []string `yaml:"availabilityZones" json:"availabilityZones,omitempty"`
+}
+
+// EKSConfig is the eks microstack's per-deployment knobs.
+type EKSConfig struct {
+	NodeCount         int    `yaml:"nodeCount" json:"nodeCount" validate:"required"`
+	NodeInstanceType  string `yaml:"nodeInstanceType" json:"nodeInstanceType" validate:"required"`
+	KubernetesVersion string `yaml:"kubernetesVersion" json:"kubernetesVersion,omitempty"`
+}
+
+// RDSConfig is the rds microstack's per-deployment knobs.
+type RDSConfig struct {
+	InstanceClass      string `yaml:"instanceClass" json:"instanceClass" validate:"required"`
+	EngineVersion      string `yaml:"engineVersion" json:"engineVersion,omitempty"`
+	AllocatedStorageGB int    `yaml:"allocatedStorageGb" json:"allocatedStorageGb,omitempty"`
+}
+
+// Route53Config is the route53 microstack's per-deployment knobs.
+type Route53Config struct {
+	HostedZones []string `yaml:"hostedZones" json:"hostedZones,omitempty"`
+}
+
+// CertificatesConfig is the certificates microstack's per-deployment knobs.
+type CertificatesConfig struct {
+	Domains []string `yaml:"domains" json:"domains,omitempty"`
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed defaults.yaml
+var defaultsFS embed.FS
+
+// overlayDir is where environment and customer overlays live, relative to
+// the working directory cmd-deploy (or the Pulumi program) is run from.
+const overlayDir = "configs"
+
+// Load resolves the effective Config for one deploy invocation:
+//
+//  1. pkg/config's embedded defaults.yaml
+//  2. configs/envs/<environment>.yaml, if present
+//  3. configs/customers/<customer>.yaml, if present
+//  4. configFile itself
+//  5. each "a.b.c=value" override in setOverrides, applied in order
+//
+// Layers 2 and 3 key off the environment/customer declared in configFile.
+// Missing overlay files are skipped, not an error. The merged tree (deep
+// right-wins) is validated against GenerateSchema() before it's returned.
+func Load(configFile string, setOverrides []string) (*Config, error) {
+	instance, err := readYAMLLayer(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	environment, _ := instance["environment"].(string)
+	customer, _ := instance["customer"].(string)
+
+	base, err := readEmbeddedYAMLLayer("defaults.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded defaults: %w", err)
+	}
+
+	envOverlay, err := readOptionalYAMLLayer(filepath.Join(overlayDir, "envs", environment+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environment overlay: %w", err)
+	}
+
+	customerOverlay, err := readOptionalYAMLLayer(filepath.Join(overlayDir, "customers", customer+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read customer overlay: %w", err)
+	}
+
+	merged := map[string]interface{}{}
+	for _, layer := range []map[string]interface{}{base, envOverlay, customerOverlay, instance} {
+		deepMerge(merged, layer)
+	}
+
+	for _, set := range setOverrides {
+		if err := applySet(merged, set); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := decode(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode merged config: %w", err)
+	}
+
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// decode round-trips the merged generic map through YAML into a Config, so
+// the deep-merged map[string]interface{} tree lands in the typed struct.
+func decode(merged map[string]interface{}) (*Config, error) {
+	raw, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func readYAMLLayer(filename string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalLayer(data)
+}
+
+func readEmbeddedYAMLLayer(name string) (map[string]interface{}, error) {
+	data, err := defaultsFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalLayer(data)
+}
+
+// readOptionalYAMLLayer returns an empty layer, not an error, when filename
+// doesn't exist — overlays are opt-in.
+func readOptionalYAMLLayer(filename string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalLayer(data)
+}
+
+func unmarshalLayer(data []byte) (map[string]interface{}, error) {
+	layer := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
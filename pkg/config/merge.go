@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deepMerge merges src into dst in place, recursing into nested maps and
+// letting src win on every other value (deep-right-wins). dst is mutated and
+// also returned for chaining.
+func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
+	for key, srcVal := range src {
+		dstVal, exists := dst[key]
+		if !exists {
+			dst[key] = srcVal
+			continue
+		}
+
+		dstMap, dstIsMap := dstVal.(map[string]interface{})
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			dst[key] = deepMerge(dstMap, srcMap)
+			continue
+		}
+
+		dst[key] = srcVal
+	}
+	return dst
+}
+
+// applySet applies a single "--set a.b.c=value" override onto root, creating
+// intermediate maps as needed. value is parsed as an integer or bool when it
+// looks like one, otherwise kept as a string.
+func applySet(root map[string]interface{}, assignment string) error {
+	path, rawValue, ok := strings.Cut(assignment, "=")
+	if !ok {
+		return fmt.Errorf("invalid --set %q: expected the form a.b.c=value", assignment)
+	}
+
+	keys := strings.Split(path, ".")
+	if len(keys) == 0 || keys[0] == "" {
+		return fmt.Errorf("invalid --set %q: empty path", assignment)
+	}
+
+	node := root
+	for _, key := range keys[:len(keys)-1] {
+		next, exists := node[key]
+		if !exists {
+			child := map[string]interface{}{}
+			node[key] = child
+			node = child
+			continue
+		}
+		childMap, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid --set %q: %q is not a map", assignment, key)
+		}
+		node = childMap
+	}
+
+	node[keys[len(keys)-1]] = parseSetValue(rawValue)
+	return nil
+}
+
+// parseSetValue interprets a --set value as a bool or integer when it looks
+// like one, otherwise leaves it as a plain string.
+func parseSetValue(raw string) interface{} {
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return i
+	}
+	if b, err := strconv.ParseBool(raw); err == nil {
+		return b
+	}
+	return raw
+}
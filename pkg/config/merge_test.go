@@ -0,0 +1,178 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst, src map[string]interface{}
+		want     map[string]interface{}
+	}{
+		{
+			name: "src wins on scalar conflict",
+			dst:  map[string]interface{}{"environment": "dev"},
+			src:  map[string]interface{}{"environment": "prod"},
+			want: map[string]interface{}{"environment": "prod"},
+		},
+		{
+			name: "keys only in dst are kept",
+			dst:  map[string]interface{}{"customer": "acme"},
+			src:  map[string]interface{}{"environment": "prod"},
+			want: map[string]interface{}{"customer": "acme", "environment": "prod"},
+		},
+		{
+			name: "nested maps merge recursively instead of replacing",
+			dst: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeCount": 3, "nodeInstanceType": "t3.medium"},
+				},
+			},
+			src: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeCount": 5},
+				},
+			},
+			want: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeCount": 5, "nodeInstanceType": "t3.medium"},
+				},
+			},
+		},
+		{
+			name: "src scalar overwrites dst map wholesale",
+			dst: map[string]interface{}{
+				"driftSeverity": map[string]interface{}{"rds": "critical"},
+			},
+			src: map[string]interface{}{
+				"driftSeverity": "disabled",
+			},
+			want: map[string]interface{}{
+				"driftSeverity": "disabled",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deepMerge(tt.dst, tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deepMerge() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySet(t *testing.T) {
+	tests := []struct {
+		name       string
+		root       map[string]interface{}
+		assignment string
+		want       map[string]interface{}
+		wantErr    bool
+	}{
+		{
+			name:       "top-level string value",
+			root:       map[string]interface{}{},
+			assignment: "environment=prod",
+			want:       map[string]interface{}{"environment": "prod"},
+		},
+		{
+			name:       "nested path creates intermediate maps",
+			root:       map[string]interface{}{},
+			assignment: "microstacks.eks.nodeCount=5",
+			want: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeCount": int64(5)},
+				},
+			},
+		},
+		{
+			name: "nested path reuses an existing map",
+			root: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeInstanceType": "t3.medium"},
+				},
+			},
+			assignment: "microstacks.eks.nodeCount=5",
+			want: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"nodeInstanceType": "t3.medium", "nodeCount": int64(5)},
+				},
+			},
+		},
+		{
+			name:       "bool value is parsed, not left as a string",
+			root:       map[string]interface{}{},
+			assignment: "microstacks.eks.enabled=true",
+			want: map[string]interface{}{
+				"microstacks": map[string]interface{}{
+					"eks": map[string]interface{}{"enabled": true},
+				},
+			},
+		},
+		{
+			name:       "missing = is an error",
+			root:       map[string]interface{}{},
+			assignment: "environment",
+			wantErr:    true,
+		},
+		{
+			name:       "empty path is an error",
+			root:       map[string]interface{}{},
+			assignment: "=prod",
+			wantErr:    true,
+		},
+		{
+			name: "path through a non-map value is an error",
+			root: map[string]interface{}{
+				"environment": "prod",
+			},
+			assignment: "environment.nested=value",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applySet(tt.root, tt.assignment)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applySet() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySet() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.root, tt.want) {
+				t.Errorf("applySet() root = %#v, want %#v", tt.root, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSetValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want interface{}
+	}{
+		{"true", true},
+		{"false", false},
+		{"42", int64(42)},
+		{"-7", int64(-7)},
+		{"t3.medium", "t3.medium"},
+		{"us-east-1", "us-east-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got := parseSetValue(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSetValue(%q) = %#v (%T), want %#v (%T)", tt.raw, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
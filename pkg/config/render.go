@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+)
+
+// knoblessMicrostacks are the microstacks pkg/microstacks deploys that have
+// no field in MicrostacksConfig. They are listed here because pkg/microstacks
+// imports this package and so cannot be imported back.
+var knoblessMicrostacks = map[string]bool{
+	"acls": true, "s3": true, "opensearch": true, "cloudfront": true,
+	"eks-addons": true, "helm-charts": true, "storage-classes": true, "ingress-classes": true,
+}
+
+// Microstack returns the resolved per-microstack config cfg.Microstacks
+// carries for name, for `cmd-deploy config render --microstack <name>` to
+// print. A nil return with a nil error means either that no merged layer,
+// the embedded defaults included, set anything for name, or that name is a
+// real microstack with no config knobs of its own.
+func (c *Config) Microstack(name string) (interface{}, error) {
+	switch name {
+	case "networking":
+		return c.Microstacks.Networking, nil
+	case "eks":
+		return c.Microstacks.EKS, nil
+	case "rds":
+		return c.Microstacks.RDS, nil
+	case "route53":
+		return c.Microstacks.Route53, nil
+	case "certificates":
+		return c.Microstacks.Certificates, nil
+	default:
+		if knoblessMicrostacks[name] {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unknown microstack: %s", name)
+	}
+}
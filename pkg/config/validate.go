@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema is a minimal JSON-Schema-shaped description of a Config-like
+// struct, generated from its `json` and `validate` tags: a map of property
+// name to nested Schema, plus which of those properties are required.
+// schemaForType renders a struct's Schema; Validate checks a decoded Config
+// against it.
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+}
+
+// GenerateSchema builds the Schema for Config by walking its fields (and any
+// nested struct/pointer-to-struct fields) with reflection. Load validates
+// every merged Config against it.
+func GenerateSchema() *Schema {
+	return schemaForType(reflect.TypeOf(Config{}))
+}
+
+func schemaForType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			s.Properties[name] = schemaForType(field.Type)
+			if field.Tag.Get("validate") == "required" {
+				s.Required = append(s.Required, name)
+			}
+		}
+		sort.Strings(s.Required)
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array"}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Validate checks cfg's merged tree against GenerateSchema(), reporting every
+// required field that's missing or left at its zero value.
+func Validate(cfg *Config) error {
+	schema := GenerateSchema()
+	v := reflect.ValueOf(*cfg)
+
+	var missing []string
+	checkRequired(schema, v, "", &missing)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("config validation failed, missing required field(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func checkRequired(schema *Schema, v reflect.Value, prefix string, missing *[]string) {
+	if schema == nil || schema.Type != "object" || !v.IsValid() {
+		return
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonFieldName(field)
+		fieldValue := v.Field(i)
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if required[name] && fieldValue.IsZero() {
+			*missing = append(*missing, path)
+		}
+
+		checkRequired(schema.Properties[name], fieldValue, path, missing)
+	}
+}
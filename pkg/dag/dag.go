@@ -0,0 +1,139 @@
+// Package dag models a deploy stage's microstacks as an explicit dependency
+// graph instead of the hard-coded serial slice pkg/microstacks.ForStage
+// used to impose, so independent microstacks can run concurrently and a
+// teardown can walk the graph in reverse.
+package dag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is one microstack in a stage's graph.
+type Node struct {
+	Name string
+	// DependsOn names other nodes in the same graph that must complete
+	// before this one starts.
+	DependsOn []string
+}
+
+// Graph is a validated, cycle-free dependency graph over a stage's nodes.
+type Graph struct {
+	names     []string // declaration order
+	dependsOn map[string][]string
+}
+
+// New validates nodes — every DependsOn target must be a known node name,
+// and the graph must be acyclic — and returns the resulting Graph.
+func New(nodes []Node) (*Graph, error) {
+	g := &Graph{dependsOn: make(map[string][]string, len(nodes))}
+
+	known := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		known[n.Name] = true
+	}
+
+	for _, n := range nodes {
+		for _, dep := range n.DependsOn {
+			if !known[dep] {
+				return nil, fmt.Errorf("node %s depends on unknown node %s", n.Name, dep)
+			}
+		}
+		g.names = append(g.names, n.Name)
+		g.dependsOn[n.Name] = append([]string(nil), n.DependsOn...)
+	}
+
+	if _, err := g.TopoOrder(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Names returns the graph's node names in declaration order.
+func (g *Graph) Names() []string {
+	return append([]string(nil), g.names...)
+}
+
+// DependsOn returns name's declared dependencies.
+func (g *Graph) DependsOn(name string) []string {
+	return append([]string(nil), g.dependsOn[name]...)
+}
+
+// dependents returns, for every node, the nodes that declare a dependency on it.
+func (g *Graph) dependents() map[string][]string {
+	dependents := make(map[string][]string, len(g.names))
+	for _, n := range g.names {
+		for _, dep := range g.dependsOn[n] {
+			dependents[dep] = append(dependents[dep], n)
+		}
+	}
+	return dependents
+}
+
+// TopoOrder returns the graph's nodes in a valid dependency order (Kahn's
+// algorithm), or an error if a cycle makes that impossible.
+func (g *Graph) TopoOrder() ([]string, error) {
+	remaining := make(map[string]int, len(g.names))
+	for _, n := range g.names {
+		remaining[n] = len(g.dependsOn[n])
+	}
+	dependents := g.dependents()
+
+	var ready []string
+	for _, n := range g.names {
+		if remaining[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	order := make([]string, 0, len(g.names))
+	for len(ready) > 0 {
+		n := ready[0]
+		ready = ready[1:]
+		order = append(order, n)
+
+		for _, child := range dependents[n] {
+			remaining[child]--
+			if remaining[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if len(order) != len(g.names) {
+		return nil, fmt.Errorf("dependency cycle detected among: %s", strings.Join(g.names, ", "))
+	}
+	return order, nil
+}
+
+// Reverse returns a new Graph with every dependency edge flipped, so a
+// teardown can process a node only after everything that depended on it is
+// already gone.
+func (g *Graph) Reverse() *Graph {
+	dependents := g.dependents()
+
+	reversed := &Graph{dependsOn: make(map[string][]string, len(g.names))}
+	for i := len(g.names) - 1; i >= 0; i-- {
+		reversed.names = append(reversed.names, g.names[i])
+	}
+	for _, n := range g.names {
+		reversed.dependsOn[n] = dependents[n]
+	}
+	return reversed
+}
+
+// DOT renders the graph in Graphviz dot format for `cmd-deploy graph`.
+func (g *Graph) DOT(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", name)
+	for _, n := range g.names {
+		fmt.Fprintf(&b, "  %q;\n", n)
+	}
+	for _, n := range g.names {
+		for _, dep := range g.dependsOn[n] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", dep, n)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,188 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewAndTopoOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []Node
+		wantErr bool
+	}{
+		{
+			name: "linear chain",
+			nodes: []Node{
+				{Name: "vpc"},
+				{Name: "eks", DependsOn: []string{"vpc"}},
+				{Name: "apps", DependsOn: []string{"eks"}},
+			},
+		},
+		{
+			name: "diamond",
+			nodes: []Node{
+				{Name: "vpc"},
+				{Name: "eks", DependsOn: []string{"vpc"}},
+				{Name: "rds", DependsOn: []string{"vpc"}},
+				{Name: "apps", DependsOn: []string{"eks", "rds"}},
+			},
+		},
+		{
+			name: "unknown dependency",
+			nodes: []Node{
+				{Name: "apps", DependsOn: []string{"missing"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			nodes: []Node{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.nodes)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("New() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			order, err := g.TopoOrder()
+			if err != nil {
+				t.Fatalf("TopoOrder() error = %v", err)
+			}
+			assertValidOrder(t, tt.nodes, order)
+		})
+	}
+}
+
+func TestReverse(t *testing.T) {
+	g, err := New([]Node{
+		{Name: "vpc"},
+		{Name: "eks", DependsOn: []string{"vpc"}},
+		{Name: "apps", DependsOn: []string{"eks"}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	reversed := g.Reverse()
+	order, err := reversed.TopoOrder()
+	if err != nil {
+		t.Fatalf("TopoOrder() on reversed graph error = %v", err)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, n := range order {
+		pos[n] = i
+	}
+	if pos["apps"] > pos["eks"] || pos["eks"] > pos["vpc"] {
+		t.Fatalf("Reverse().TopoOrder() = %v, want apps before eks before vpc", order)
+	}
+}
+
+// assertValidOrder fails t if order doesn't place every node after all of
+// its declared dependencies.
+func assertValidOrder(t *testing.T, nodes []Node, order []string) {
+	t.Helper()
+
+	if len(order) != len(nodes) {
+		t.Fatalf("order has %d nodes, want %d", len(order), len(nodes))
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, n := range order {
+		pos[n] = i
+	}
+
+	dependsOn := make(map[string][]string, len(nodes))
+	for _, n := range nodes {
+		dependsOn[n.Name] = n.DependsOn
+	}
+
+	for _, n := range nodes {
+		for _, dep := range dependsOn[n.Name] {
+			if pos[dep] > pos[n.Name] {
+				t.Errorf("node %s scheduled before its dependency %s: order = %v", n.Name, dep, order)
+			}
+		}
+	}
+}
+
+func TestRunCancelPropagation(t *testing.T) {
+	g, err := New([]Node{
+		{Name: "vpc"},
+		{Name: "eks", DependsOn: []string{"vpc"}},
+		{Name: "apps", DependsOn: []string{"eks"}},
+		{Name: "rds", DependsOn: []string{"vpc"}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	failErr := errors.New("boom")
+	results := Run(context.Background(), g, 0, func(_ context.Context, name string) (map[string]int, error) {
+		if name == "eks" {
+			return nil, failErr
+		}
+		return nil, nil
+	})
+
+	status := make(map[string]Status, len(results))
+	for _, r := range results {
+		status[r.Name] = r.Status
+	}
+
+	if status["vpc"] != StatusSucceeded {
+		t.Errorf("vpc status = %s, want %s", status["vpc"], StatusSucceeded)
+	}
+	if status["eks"] != StatusFailed {
+		t.Errorf("eks status = %s, want %s", status["eks"], StatusFailed)
+	}
+	if status["apps"] != StatusCanceled {
+		t.Errorf("apps status = %s, want %s (its dependency eks failed)", status["apps"], StatusCanceled)
+	}
+	if status["rds"] != StatusSucceeded {
+		t.Errorf("rds status = %s, want %s (independent of the failed node)", status["rds"], StatusSucceeded)
+	}
+}
+
+func TestRunAllSucceed(t *testing.T) {
+	g, err := New([]Node{
+		{Name: "vpc"},
+		{Name: "eks", DependsOn: []string{"vpc"}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var ran []string
+	results := Run(context.Background(), g, 0, func(_ context.Context, name string) (map[string]int, error) {
+		ran = append(ran, name)
+		return map[string]int{"create": 1}, nil
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	for _, r := range results {
+		if r.Status != StatusSucceeded {
+			t.Errorf("node %s status = %s, want %s", r.Name, r.Status, StatusSucceeded)
+		}
+	}
+	if ran[0] != "vpc" {
+		t.Errorf("ran[0] = %s, want vpc before its dependent", ran[0])
+	}
+}
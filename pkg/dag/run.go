@@ -0,0 +1,141 @@
+package dag
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is a node's terminal state from a Run.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusCanceled marks a node that was never started because one of its
+	// (possibly transitive) dependencies failed.
+	StatusCanceled Status = "canceled"
+)
+
+// Result is one node's outcome from a Run.
+type Result struct {
+	Name            string
+	Status          Status
+	Duration        time.Duration
+	ResourceChanges map[string]int
+	Err             error
+}
+
+// NodeFunc executes a single node and reports its resource-change counts
+// (nil if not applicable, e.g. a preview).
+type NodeFunc func(ctx context.Context, name string) (map[string]int, error)
+
+// Run executes every node in g with up to parallelism concurrent workers,
+// starting a node only once all its DependsOn have succeeded. If a node
+// fails, every not-yet-started node that (transitively) depends on it is
+// reported StatusCanceled instead of run; independent siblings still run to
+// completion. parallelism <= 0 means unbounded.
+func Run(ctx context.Context, g *Graph, parallelism int, fn NodeFunc) []Result {
+	names := g.names
+	dependents := g.dependents()
+
+	if parallelism <= 0 || parallelism > len(names) {
+		parallelism = len(names)
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+
+	var (
+		mu             sync.Mutex
+		wg             sync.WaitGroup
+		remaining      = make(map[string]int, len(names))
+		ancestorFailed = make(map[string]bool, len(names))
+		resultByName   = make(map[string]Result, len(names))
+	)
+	for _, n := range names {
+		remaining[n] = len(g.dependsOn[n])
+	}
+
+	var schedule func(name string)
+	schedule = func(name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			mu.Lock()
+			skip := ancestorFailed[name]
+			mu.Unlock()
+
+			var result Result
+			if skip {
+				result = Result{Name: name, Status: StatusCanceled}
+			} else {
+				sem <- struct{}{}
+				start := time.Now()
+				changes, err := fn(ctx, name)
+				<-sem
+
+				result = Result{
+					Name:            name,
+					Duration:        time.Since(start),
+					ResourceChanges: changes,
+					Err:             err,
+				}
+				if err != nil {
+					result.Status = StatusFailed
+				} else {
+					result.Status = StatusSucceeded
+				}
+			}
+
+			mu.Lock()
+			resultByName[name] = result
+			if result.Status != StatusSucceeded {
+				markDescendantsFailed(name, dependents, ancestorFailed)
+			}
+			var toSchedule []string
+			for _, child := range dependents[name] {
+				remaining[child]--
+				if remaining[child] == 0 {
+					toSchedule = append(toSchedule, child)
+				}
+			}
+			mu.Unlock()
+
+			for _, child := range toSchedule {
+				schedule(child)
+			}
+		}()
+	}
+
+	var roots []string
+	for _, n := range names {
+		if remaining[n] == 0 {
+			roots = append(roots, n)
+		}
+	}
+	for _, n := range roots {
+		schedule(n)
+	}
+	wg.Wait()
+
+	results := make([]Result, 0, len(names))
+	for _, n := range names {
+		results = append(results, resultByName[n])
+	}
+	return results
+}
+
+// markDescendantsFailed flags every (transitive) dependent of name so Run
+// reports it StatusCanceled instead of starting it. Callers must hold the
+// caller's mutex.
+func markDescendantsFailed(name string, dependents map[string][]string, ancestorFailed map[string]bool) {
+	for _, child := range dependents[name] {
+		if !ancestorFailed[child] {
+			ancestorFailed[child] = true
+			markDescendantsFailed(child, dependents, ancestorFailed)
+		}
+	}
+}
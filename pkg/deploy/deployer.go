@@ -0,0 +1,170 @@
+// Package deploy drives Pulumi updates through the Automation API instead of
+// shelling out to the pulumi binary, so cmd-deploy gets structured results
+// and streamed engine events instead of scraped CLI output.
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/microstacks"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+const project = "aws-infra-pulumi-go"
+
+// Deployer drives Automation API updates for a single microstack's stack,
+// backed by the shared S3 state bucket.
+type Deployer struct {
+	stack auto.Stack
+}
+
+// New creates or selects the stack for microstack and binds it to the
+// S3-backed Pulumi backend, registering cfg's deploy function inline so
+// Automation can run it in-process without a separate `pulumi up`.
+func New(ctx context.Context, cfg *microstacks.Config, stackName, bucketName, region, microstack string) (*Deployer, error) {
+	run, err := microstacks.RunFunc(cfg, microstack)
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := auto.UpsertStackInlineSource(ctx, stackName, project, run,
+		auto.EnvVars(map[string]string{
+			"PULUMI_BACKEND_URL": fmt.Sprintf("s3://%s", bucketName),
+			"AWS_REGION":         region,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create or select stack %s: %w", stackName, err)
+	}
+
+	if err := stack.SetConfig(ctx, "microstack", auto.ConfigValue{Value: microstack}); err != nil {
+		return nil, fmt.Errorf("failed to set microstack config: %w", err)
+	}
+
+	return &Deployer{stack: stack}, nil
+}
+
+// Up runs `pulumi up` for the microstack. With jsonOutput, engine events are
+// streamed to stdout as newline-delimited JSON instead of human-readable
+// progress text, for callers that consume cmd-deploy's output programmatically.
+func (d *Deployer) Up(ctx context.Context, jsonOutput bool) (auto.UpResult, error) {
+	if jsonOutput {
+		ch, done := streamEventsJSON()
+		result, err := d.stack.Up(ctx, optup.EventStreams(ch))
+		<-done
+		return result, err
+	}
+	return d.stack.Up(ctx, optup.ProgressStreams(os.Stdout))
+}
+
+// Preview runs `pulumi preview` for the microstack, in the same jsonOutput
+// modes as Up.
+func (d *Deployer) Preview(ctx context.Context, jsonOutput bool) (auto.PreviewResult, error) {
+	if jsonOutput {
+		ch, done := streamEventsJSON()
+		result, err := d.stack.Preview(ctx, optpreview.EventStreams(ch))
+		<-done
+		return result, err
+	}
+	return d.stack.Preview(ctx, optpreview.ProgressStreams(os.Stdout))
+}
+
+// Refresh reconciles stack state with the real infrastructure, in the same
+// jsonOutput modes as Up.
+func (d *Deployer) Refresh(ctx context.Context, jsonOutput bool) (auto.RefreshResult, error) {
+	if jsonOutput {
+		ch, done := streamEventsJSON()
+		result, err := d.stack.Refresh(ctx, optrefresh.EventStreams(ch))
+		<-done
+		return result, err
+	}
+	return d.stack.Refresh(ctx, optrefresh.ProgressStreams(os.Stdout))
+}
+
+// ResourceChange is one resource's URN and the op a preview/refresh/up would
+// apply to it, captured off the engine event stream at per-resource
+// granularity beyond the aggregate Summary.ResourceChanges op-type counts.
+type ResourceChange struct {
+	URN string
+	Op  string
+}
+
+// RefreshPreview runs `pulumi refresh --preview-only`: it reports what
+// Refresh would change to reconcile state with real infrastructure without
+// writing anything back, plus the per-resource URN+op pairs behind that
+// summary. pkg/drift uses both to detect out-of-band changes without
+// disturbing the stack's recorded state.
+func (d *Deployer) RefreshPreview(ctx context.Context) (auto.RefreshResult, []ResourceChange, error) {
+	ch := make(chan events.EngineEvent)
+	var changedResources []ResourceChange
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for e := range ch {
+			if e.ResourcePreEvent == nil {
+				continue
+			}
+			op := string(e.ResourcePreEvent.Metadata.Op)
+			if op == "same" {
+				continue
+			}
+			changedResources = append(changedResources, ResourceChange{
+				URN: e.ResourcePreEvent.Metadata.URN,
+				Op:  op,
+			})
+		}
+	}()
+
+	result, err := d.stack.Refresh(ctx,
+		optrefresh.PreviewOnly(),
+		optrefresh.ProgressStreams(os.Stdout),
+		optrefresh.EventStreams(ch),
+	)
+	<-done
+
+	return result, changedResources, err
+}
+
+// Destroy tears down the microstack's resources, in the same jsonOutput
+// modes as Up.
+func (d *Deployer) Destroy(ctx context.Context, jsonOutput bool) (auto.DestroyResult, error) {
+	if jsonOutput {
+		ch, done := streamEventsJSON()
+		result, err := d.stack.Destroy(ctx, optdestroy.EventStreams(ch))
+		<-done
+		return result, err
+	}
+	return d.stack.Destroy(ctx, optdestroy.ProgressStreams(os.Stdout))
+}
+
+// streamEventsJSON wires an Automation API EventStreams channel and drains
+// it to stdout as newline-delimited JSON, one engine event per line, so
+// scripted callers can consume cmd-deploy's output without scraping text.
+// The Automation API closes the channel when the operation finishes; the
+// returned done channel closes once every event up to that point has been
+// encoded, so callers can safely read the operation's result afterward.
+func streamEventsJSON() (chan events.EngineEvent, <-chan struct{}) {
+	ch := make(chan events.EngineEvent)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		enc := json.NewEncoder(os.Stdout)
+		for e := range ch {
+			if err := enc.Encode(e); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to encode engine event: %v\n", err)
+			}
+		}
+	}()
+
+	return ch, done
+}
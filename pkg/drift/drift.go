@@ -0,0 +1,201 @@
+// Package drift detects out-of-band infrastructure changes by running
+// `pulumi refresh --preview-only` across a stage's microstacks through the
+// Automation API and diffing the result against the last-known snapshot
+// persisted in the state bucket.
+package drift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/deploy"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/microstacks"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/refs"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// Severity classifies how urgently a drifted microstack needs attention.
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// severityFor looks up cfg.DriftSeverity for microstack, defaulting to
+// SeverityWarning when the microstack isn't listed.
+func severityFor(cfg *microstacks.Config, microstack string) Severity {
+	if cfg.DriftSeverity != nil {
+		if s, ok := cfg.DriftSeverity[microstack]; ok && strings.EqualFold(s, string(SeverityCritical)) {
+			return SeverityCritical
+		}
+	}
+	return SeverityWarning
+}
+
+// ResourceDrift is one resource's out-of-band change detected by a drift
+// check, the per-resource identity behind StackDrift.Changes' op-type
+// counts. Status distinguishes drift that's new since the last recorded
+// snapshot from drift that was already present and is merely persisting.
+type ResourceDrift struct {
+	URN    string `json:"urn"`
+	Op     string `json:"op"`     // the pulumi op RefreshPreview would apply: "update", "create", "delete"
+	Status string `json:"status"` // "new" or "persisting"
+}
+
+// StackDrift is one microstack's drift result for a single check.
+type StackDrift struct {
+	Microstack string          `json:"microstack"`
+	StackName  string          `json:"stackName"`
+	Severity   Severity        `json:"severity"`
+	HasDrift   bool            `json:"hasDrift"`
+	Changes    map[string]int  `json:"changes"` // keyed by pulumi op type, e.g. "update", "create", "delete"
+	Resources  []ResourceDrift `json:"resources,omitempty"`
+}
+
+// Report is the structured result of one drift check across a stage.
+type Report struct {
+	Stage       string       `json:"stage"`
+	CheckedAt   time.Time    `json:"checkedAt"`
+	Microstacks []StackDrift `json:"microstacks"`
+}
+
+// HasDrift reports whether any microstack in the report drifted.
+func (r Report) HasDrift() bool {
+	for _, sd := range r.Microstacks {
+		if sd.HasDrift {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the human-readable form of the report printed to stdout
+// and sent to Notifiers that don't have their own structured view.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Drift report for stage %q at %s\n", r.Stage, r.CheckedAt.Format(time.RFC3339))
+	for _, sd := range r.Microstacks {
+		status := "no drift"
+		if sd.HasDrift {
+			status = fmt.Sprintf("DRIFT (%s): %v", sd.Severity, sd.Changes)
+		}
+		fmt.Fprintf(&b, "  %-20s %s — %s\n", sd.Microstack, sd.StackName, status)
+		for _, rd := range sd.Resources {
+			fmt.Fprintf(&b, "      %-8s %-11s %s\n", rd.Op, rd.Status, rd.URN)
+		}
+	}
+	return b.String()
+}
+
+// Checker runs drift detection for a stage's microstacks, persisting each
+// run's snapshot via History so the next run can diff against it.
+type Checker struct {
+	cfg        *microstacks.Config
+	bucketName string
+	region     string
+	history    *History
+	notifiers  []Notifier
+}
+
+// NewChecker builds a Checker that reads/writes drift history under the
+// drift-history/ prefix of bucketName and notifies notifiers after every run.
+func NewChecker(cfg *microstacks.Config, bucketName, region string, notifiers ...Notifier) *Checker {
+	return &Checker{
+		cfg:        cfg,
+		bucketName: bucketName,
+		region:     region,
+		history:    NewHistory(bucketName, region),
+		notifiers:  notifiers,
+	}
+}
+
+// Run refreshes every microstack in stage, compares the result against the
+// last snapshot recorded for that microstack, persists the new snapshot, and
+// notifies all registered Notifiers before returning the Report.
+func (c *Checker) Run(ctx context.Context, stage string) (Report, error) {
+	report := Report{Stage: stage, CheckedAt: time.Now()}
+
+	for _, microstack := range microstacks.ForStage(stage) {
+		region := c.region
+		stackName := refs.StackName(c.cfg.Customer, stage, microstack, region)
+
+		d, err := deploy.New(ctx, c.cfg, stackName, c.bucketName, region, microstack)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to prepare stack %s: %w", stackName, err)
+		}
+
+		result, changedResources, err := d.RefreshPreview(ctx)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to preview refresh for %s: %w", stackName, err)
+		}
+		changes := resourceChanges(result)
+
+		previous, hasPrevious, err := c.history.Latest(ctx, stackName)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to read drift history for %s: %w", stackName, err)
+		}
+		previouslyDrifted := make(map[string]bool, len(previous.Resources))
+		if hasPrevious {
+			for _, rd := range previous.Resources {
+				previouslyDrifted[rd.URN] = true
+			}
+		}
+
+		resources := make([]ResourceDrift, 0, len(changedResources))
+		for _, rc := range changedResources {
+			status := "new"
+			if previouslyDrifted[rc.URN] {
+				status = "persisting"
+			}
+			resources = append(resources, ResourceDrift{URN: rc.URN, Op: rc.Op, Status: status})
+		}
+
+		sd := StackDrift{
+			Microstack: microstack,
+			StackName:  stackName,
+			Severity:   severityFor(c.cfg, microstack),
+			HasDrift:   hasNonNoOpChanges(changes),
+			Changes:    changes,
+			Resources:  resources,
+		}
+		report.Microstacks = append(report.Microstacks, sd)
+
+		if err := c.history.Put(ctx, stackName, sd); err != nil {
+			return Report{}, fmt.Errorf("failed to persist drift snapshot for %s: %w", stackName, err)
+		}
+	}
+
+	for _, n := range c.notifiers {
+		if err := n.Notify(ctx, report); err != nil {
+			return report, fmt.Errorf("failed to notify drift report: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// resourceChanges extracts the op-type change counts from a RefreshResult's
+// update summary, the same map[string]int shape used across Up/Refresh/Destroy.
+func resourceChanges(result auto.RefreshResult) map[string]int {
+	if result.Summary.ResourceChanges == nil {
+		return map[string]int{}
+	}
+	return *result.Summary.ResourceChanges
+}
+
+// hasNonNoOpChanges reports whether changes contains anything other than a
+// "same" (no-op) count.
+func hasNonNoOpChanges(changes map[string]int) bool {
+	for op, count := range changes {
+		if op == "same" {
+			continue
+		}
+		if count > 0 {
+			return true
+		}
+	}
+	return false
+}
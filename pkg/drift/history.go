@@ -0,0 +1,110 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// historyPrefix is the key prefix under the shared Pulumi state bucket where
+// every drift check's snapshot is written, so trends can be audited later.
+const historyPrefix = "drift-history"
+
+// History persists per-microstack drift snapshots to the existing state
+// bucket, keyed by stack name and check time.
+type History struct {
+	bucketName string
+	region     string
+}
+
+// NewHistory returns a History backed by bucketName in region.
+func NewHistory(bucketName, region string) *History {
+	return &History{bucketName: bucketName, region: region}
+}
+
+// Put writes sd as a timestamped JSON object under
+// drift-history/<stackName>/<RFC3339 timestamp>.json.
+func (h *History) Put(ctx context.Context, stackName string, sd StackDrift) error {
+	body, err := json.MarshalIndent(sd, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift snapshot for %s: %w", stackName, err)
+	}
+
+	client, err := h.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%s/%s.json", historyPrefix, stackName, time.Now().UTC().Format(time.RFC3339))
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(h.bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write drift snapshot to s3://%s/%s: %w", h.bucketName, key, err)
+	}
+	return nil
+}
+
+// Latest returns the most recently persisted drift snapshot for stackName,
+// so Checker.Run can diff the current check against it. ok is false if no
+// snapshot has been recorded yet, e.g. the stack's first drift check.
+func (h *History) Latest(ctx context.Context, stackName string) (sd StackDrift, ok bool, err error) {
+	client, err := h.client(ctx)
+	if err != nil {
+		return StackDrift{}, false, err
+	}
+
+	prefix := fmt.Sprintf("%s/%s/", historyPrefix, stackName)
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(h.bucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	// Keys are named by RFC3339 timestamp, so the lexicographically largest
+	// key is also the most recent.
+	var latestKey string
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return StackDrift{}, false, fmt.Errorf("failed to list drift history for %s: %w", stackName, err)
+		}
+		for _, obj := range out.Contents {
+			if key := aws.ToString(obj.Key); key > latestKey {
+				latestKey = key
+			}
+		}
+	}
+	if latestKey == "" {
+		return StackDrift{}, false, nil
+	}
+
+	getOut, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(h.bucketName),
+		Key:    aws.String(latestKey),
+	})
+	if err != nil {
+		return StackDrift{}, false, fmt.Errorf("failed to read drift snapshot s3://%s/%s: %w", h.bucketName, latestKey, err)
+	}
+	defer getOut.Body.Close()
+
+	if err := json.NewDecoder(getOut.Body).Decode(&sd); err != nil {
+		return StackDrift{}, false, fmt.Errorf("failed to decode drift snapshot s3://%s/%s: %w", h.bucketName, latestKey, err)
+	}
+	return sd, true, nil
+}
+
+func (h *History) client(ctx context.Context) (*s3.Client, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(h.region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
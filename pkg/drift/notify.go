@@ -0,0 +1,99 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// Notifier delivers a drift Report to an operator-facing channel. Checker.Run
+// calls every registered Notifier once per check, regardless of whether the
+// report found drift, so a Notifier that only cares about drifted runs should
+// check Report.HasDrift itself.
+type Notifier interface {
+	Notify(ctx context.Context, report Report) error
+}
+
+// StdoutNotifier prints the human-readable report to stdout. NewChecker does
+// not add it on its own; a caller registers it like any other Notifier.
+type StdoutNotifier struct{}
+
+// Notify implements Notifier.
+func (StdoutNotifier) Notify(_ context.Context, report Report) error {
+	fmt.Print(report.String())
+	return nil
+}
+
+// SNSNotifier publishes the report as a JSON message to an SNS topic, for
+// routing to whatever an operator's existing AWS alerting already fans out
+// to (email, PagerDuty, a Lambda, ...).
+type SNSNotifier struct {
+	TopicARN string
+	Region   string
+}
+
+// Notify implements Notifier.
+func (n SNSNotifier) Notify(ctx context.Context, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift report: %w", err)
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(n.Region))
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := sns.NewFromConfig(awsCfg)
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.TopicARN),
+		Subject:  aws.String(fmt.Sprintf("Drift report: %s", report.Stage)),
+		Message:  aws.String(string(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish drift report to %s: %w", n.TopicARN, err)
+	}
+	return nil
+}
+
+// SlackNotifier posts the human-readable report to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+// Notify implements Notifier.
+func (n SlackNotifier) Notify(ctx context.Context, report Report) error {
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	payload, err := json.Marshal(map[string]string{"text": "```" + report.String() + "```"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build slack webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post drift report to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
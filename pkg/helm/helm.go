@@ -0,0 +1,124 @@
+// Package helm reconciles a curated list of Helm releases onto the EKS
+// cluster from a declarative YAML manifest, so adding a chart or cluster
+// addon is an edit to that manifest instead of a Go change. Both
+// deployHelmCharts and deployEKSAddons in pkg/microstacks read through this
+// package against their own manifest file.
+package helm
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValueRef resolves one Helm value from a prior microstack's stack output,
+// via refs.GetOutput, instead of a literal in Values.
+type ValueRef struct {
+	// Stage and Microstack identify the stack the output was exported from,
+	// e.g. Stage: "vpc", Microstack: "networking".
+	Stage      string `yaml:"stage"`
+	Microstack string `yaml:"microstack"`
+	// Output is the stack output key, e.g. "vpcId".
+	Output string `yaml:"output"`
+	// Key is the dotted path this output is written to in the release's
+	// Helm values, e.g. "vpc.id".
+	Key string `yaml:"key"`
+}
+
+// Release is one Helm chart this package reconciles.
+type Release struct {
+	Name      string `yaml:"name"`
+	Repo      string `yaml:"repo"`
+	Chart     string `yaml:"chart"`
+	Version   string `yaml:"version"`
+	// Digest pins the chart to a specific content digest (chart@sha256:...)
+	// instead of trusting Version alone.
+	Digest      string                 `yaml:"digest,omitempty"`
+	Namespace   string                 `yaml:"namespace"`
+	Values      map[string]interface{} `yaml:"values,omitempty"`
+	ValuesFrom  []ValueRef             `yaml:"valuesFrom,omitempty"`
+	DependsOn   []string               `yaml:"dependsOn,omitempty"`
+	WaitForJobs bool                   `yaml:"waitForJobs,omitempty"`
+	// Disabled skips the release entirely without deleting it from the
+	// manifest, for temporarily turning a chart off.
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// Manifest is the top-level shape of a helm-charts.yaml / eks-addons.yaml file.
+type Manifest struct {
+	Releases []Release `yaml:"releases"`
+}
+
+// LoadManifest reads and parses a manifest file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read helm manifest %s: %w", path, err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse helm manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Ordered returns the manifest's non-Disabled releases topologically sorted
+// so every release comes after everything in its DependsOn.
+func (m *Manifest) Ordered() ([]Release, error) {
+	byName := make(map[string]Release, len(m.Releases))
+	for _, r := range m.Releases {
+		if r.Disabled {
+			continue
+		}
+		if _, dup := byName[r.Name]; dup {
+			return nil, fmt.Errorf("duplicate release name: %s", r.Name)
+		}
+		byName[r.Name] = r
+	}
+
+	var (
+		ordered  []Release
+		visited  = map[string]bool{}
+		visiting = map[string]bool{}
+	)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("dependsOn cycle detected at release %s", name)
+		}
+		r, ok := byName[name]
+		if !ok {
+			// Dependency on a disabled or unknown release; nothing to order.
+			return nil
+		}
+
+		visiting[name] = true
+		for _, dep := range r.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+
+		visited[name] = true
+		ordered = append(ordered, r)
+		return nil
+	}
+
+	for _, r := range m.Releases {
+		if r.Disabled {
+			continue
+		}
+		if err := visit(r.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,97 @@
+package helm
+
+import "testing"
+
+func TestManifestOrdered(t *testing.T) {
+	tests := []struct {
+		name     string
+		releases []Release
+		want     []string // release names, in expected order
+		wantErr  bool
+	}{
+		{
+			name: "independent releases keep declaration order",
+			releases: []Release{
+				{Name: "cert-manager"},
+				{Name: "external-dns"},
+			},
+			want: []string{"cert-manager", "external-dns"},
+		},
+		{
+			name: "dependents come after their dependency",
+			releases: []Release{
+				{Name: "external-dns", DependsOn: []string{"cert-manager"}},
+				{Name: "cert-manager"},
+			},
+			want: []string{"cert-manager", "external-dns"},
+		},
+		{
+			name: "disabled releases are skipped",
+			releases: []Release{
+				{Name: "cert-manager"},
+				{Name: "old-chart", Disabled: true},
+			},
+			want: []string{"cert-manager"},
+		},
+		{
+			name: "dependency on a disabled release is ignored, not an error",
+			releases: []Release{
+				{Name: "old-chart", Disabled: true},
+				{Name: "external-dns", DependsOn: []string{"old-chart"}},
+			},
+			want: []string{"external-dns"},
+		},
+		{
+			name: "duplicate release name is an error",
+			releases: []Release{
+				{Name: "cert-manager"},
+				{Name: "cert-manager"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dependsOn cycle is an error",
+			releases: []Release{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{Releases: tt.releases}
+			ordered, err := m.Ordered()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Ordered() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Ordered() error = %v", err)
+			}
+
+			names := make([]string, len(ordered))
+			for i, r := range ordered {
+				names[i] = r.Name
+			}
+			if !equalStrings(names, tt.want) {
+				t.Errorf("Ordered() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/refs"
+	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes/helm/v3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Reconcile installs or updates every non-Disabled release in the manifest
+// at manifestPath, in DependsOn order, resolving each release's ValuesFrom
+// entries against customer/region's prior stacks first. opts (typically a
+// pulumi.Provider pointed at the EKS cluster) is applied to every release.
+func Reconcile(ctx *pulumi.Context, manifestPath, customer, region string, opts ...pulumi.ResourceOption) error {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	releases, err := manifest.Ordered()
+	if err != nil {
+		return fmt.Errorf("failed to order helm releases in %s: %w", manifestPath, err)
+	}
+
+	installed := make(map[string]pulumi.Resource, len(releases))
+	for _, r := range releases {
+		values, err := resolveValues(ctx, r, customer, region)
+		if err != nil {
+			return fmt.Errorf("failed to resolve values for release %s: %w", r.Name, err)
+		}
+
+		releaseOpts := append([]pulumi.ResourceOption{}, opts...)
+		if deps := dependencyResources(r, installed); len(deps) > 0 {
+			releaseOpts = append(releaseOpts, pulumi.DependsOn(deps))
+		}
+
+		chart := r.Chart
+		if r.Digest != "" {
+			chart = fmt.Sprintf("%s@%s", r.Chart, r.Digest)
+		}
+
+		release, err := helmv3.NewRelease(ctx, r.Name, &helmv3.ReleaseArgs{
+			Name:      pulumi.String(r.Name),
+			Chart:     pulumi.String(chart),
+			Version:   pulumi.String(r.Version),
+			Namespace: pulumi.String(r.Namespace),
+			RepositoryOpts: helmv3.RepositoryOptsArgs{
+				Repo: pulumi.String(r.Repo),
+			},
+			Values:      values,
+			WaitForJobs: pulumi.Bool(r.WaitForJobs),
+		}, releaseOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to install release %s: %w", r.Name, err)
+		}
+
+		installed[r.Name] = release
+	}
+
+	return nil
+}
+
+// dependencyResources looks up the already-installed pulumi.Resources for
+// r.DependsOn, so Reconcile can chain pulumi.DependsOn and let the engine
+// enforce ordering on top of the DependsOn it already used to sort releases.
+func dependencyResources(r Release, installed map[string]pulumi.Resource) []pulumi.Resource {
+	var deps []pulumi.Resource
+	for _, name := range r.DependsOn {
+		if res, ok := installed[name]; ok {
+			deps = append(deps, res)
+		}
+	}
+	return deps
+}
+
+// resolveValues builds a release's Helm values, overlaying every ValuesFrom
+// entry's resolved stack output onto the literal Values map.
+func resolveValues(ctx *pulumi.Context, r Release, customer, region string) (pulumi.Map, error) {
+	values := literalValues(r.Values)
+
+	for _, vf := range r.ValuesFrom {
+		output, err := refs.GetOutput(ctx, customer, vf.Stage, vf.Microstack, region, vf.Output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve valuesFrom %s/%s.%s: %w", vf.Stage, vf.Microstack, vf.Output, err)
+		}
+		setNested(values, vf.Key, output)
+	}
+
+	return values, nil
+}
+
+// literalValues converts a release's literal Values into a pulumi.Map,
+// keeping nested maps as pulumi.Map so setNested can overlay into them.
+func literalValues(raw map[string]interface{}) pulumi.Map {
+	values := pulumi.Map{}
+	for k, v := range raw {
+		if nested, ok := v.(map[string]interface{}); ok {
+			values[k] = literalValues(nested)
+			continue
+		}
+		values[k] = pulumi.Any(v)
+	}
+	return values
+}
+
+// setNested writes value at dottedKey's path into root, creating
+// intermediate pulumi.Map nodes as needed.
+func setNested(root pulumi.Map, dottedKey string, value pulumi.Input) {
+	keys := strings.Split(dottedKey, ".")
+	node := root
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key].(pulumi.Map)
+		if !ok {
+			child = pulumi.Map{}
+			node[key] = child
+		}
+		node = child
+	}
+	node[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,202 @@
+// Package microstacks holds the per-microstack Pulumi programs and the
+// stage/microstack topology that both the Pulumi program entrypoint and the
+// cmd-deploy CLI need to agree on.
+package microstacks
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/config"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/helm"
+	"github.com/DimArmen/aws-infra-pulumi-go/pkg/refs"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Config is the infrastructure configuration deploy functions read from, as
+// resolved by config.Load's layered defaults/environment/customer/--set
+// merge. Aliased here so the rest of this package, cmd-deploy, and the
+// Pulumi program entrypoint can keep referring to microstacks.Config.
+type Config = config.Config
+
+// DeployFunc implements a single microstack's Pulumi program.
+type DeployFunc func(ctx *pulumi.Context, cfg *Config) error
+
+// Deployers maps every known microstack name to its DeployFunc.
+var Deployers = map[string]DeployFunc{
+	"networking":      deployNetworking,
+	"acls":            deployACLs,
+	"s3":              deployS3,
+	"route53":         deployRoute53,
+	"rds":             deployRDS,
+	"eks":             deployEKS,
+	"opensearch":      deployOpenSearch,
+	"cloudfront":      deployCloudFront,
+	"certificates":    deployCertificates,
+	"eks-addons":      deployEKSAddons,
+	"helm-charts":     deployHelmCharts,
+	"storage-classes": deployStorageClasses,
+	"ingress-classes": deployIngressClasses,
+}
+
+var stages = map[string][]string{
+	"vpc":  {"networking", "acls"},
+	"core": {"s3", "route53", "rds", "eks", "opensearch", "cloudfront", "certificates"},
+	"apps": {"eks-addons", "helm-charts", "storage-classes", "ingress-classes"},
+}
+
+// ForStage returns the microstacks that make up a stage, in declaration order.
+func ForStage(stage string) []string {
+	return stages[stage]
+}
+
+// StageOf returns the stage a microstack belongs to, or "" if unknown.
+func StageOf(microstack string) string {
+	for stage, microstacksInStage := range stages {
+		for _, ms := range microstacksInStage {
+			if ms == microstack {
+				return stage
+			}
+		}
+	}
+	return ""
+}
+
+// intraStageDependsOn declares which microstacks within their own stage must
+// complete before a given microstack starts, for pkg/dag to build each
+// stage's execution graph. Microstacks with no entry have no intra-stage
+// dependencies and can run concurrently with their stage's other
+// independent microstacks. Cross-stage ordering (vpc before core before
+// apps) is not modelled here: cmd-deploy runs a single stage per invocation.
+var intraStageDependsOn = map[string][]string{
+	"helm-charts":     {"eks-addons"},
+	"ingress-classes": {"eks-addons"},
+}
+
+// DependsOn returns microstack's declared intra-stage dependencies.
+func DependsOn(microstack string) []string {
+	return intraStageDependsOn[microstack]
+}
+
+// RunFunc builds the pulumi.RunFunc for a single microstack, closing over the
+// resolved Config so the Automation API can register it directly instead of
+// shelling out to a separate `pulumi up` process.
+func RunFunc(cfg *Config, microstack string) (pulumi.RunFunc, error) {
+	deploy, ok := Deployers[microstack]
+	if !ok {
+		return nil, fmt.Errorf("unknown microstack: %s", microstack)
+	}
+	return func(ctx *pulumi.Context) error {
+		return deploy(ctx, cfg)
+	}, nil
+}
+
+// VPC Stage Functions
+func deployNetworking(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Networking microstack", nil)
+	// TODO: Implement VPC, subnets, NAT gateways, etc. using
+	// cfg.Microstacks.Networking.VpcCIDR and AvailabilityZones. Exported
+	// under the refs package's stable keys so other microstacks can consume
+	// them via a StackReference.
+	ctx.Export(refs.NetworkingVpcIDKey, pulumi.String("").ToStringOutput())
+	ctx.Export(refs.NetworkingPrivateSubnetIDsKey, pulumi.StringArray{}.ToStringArrayOutput())
+	ctx.Export(refs.NetworkingPublicSubnetIDsKey, pulumi.StringArray{}.ToStringArrayOutput())
+	return nil
+}
+
+func deployACLs(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying ACLs microstack", nil)
+	// TODO: Implement Network ACLs and security groups. Exported under the
+	// refs package's stable key so deployRDS and friends can consume it.
+	ctx.Export(refs.ACLsSecurityGroupIDsKey, pulumi.StringArray{}.ToStringArrayOutput())
+	return nil
+}
+
+// Core Stage Functions
+func deployS3(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying S3 microstack", nil)
+	// TODO: Implement S3 buckets and policies
+	return nil
+}
+
+func deployRoute53(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Route53 microstack", nil)
+	// TODO: Implement DNS and hosted zones
+	return nil
+}
+
+func deployRDS(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying RDS microstack", nil)
+	acls, err := refs.GetACLs(ctx, cfg.Customer, os.Getenv("AWS_REGION"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve acls outputs: %w", err)
+	}
+	_ = acls // TODO: attach SecurityGroupIDs and cfg.Microstacks.RDS (InstanceClass, EngineVersion, AllocatedStorageGB) to the database instances
+	return nil
+}
+
+func deployEKS(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying EKS microstack", nil)
+	networking, err := refs.GetNetworking(ctx, cfg.Customer, os.Getenv("AWS_REGION"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve networking outputs: %w", err)
+	}
+	_ = networking // TODO: place the cluster and node groups in VpcID/PrivateSubnetIDs
+	return nil
+}
+
+func deployOpenSearch(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying OpenSearch microstack", nil)
+	// TODO: Implement search and analytics
+	return nil
+}
+
+func deployCloudFront(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying CloudFront microstack", nil)
+	// TODO: Implement CDN and distributions
+	return nil
+}
+
+func deployCertificates(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Certificates microstack", nil)
+	// TODO: Implement SSL/TLS certificates
+	return nil
+}
+
+// Apps Stage Functions
+
+// eksAddonsManifest and helmChartsManifest are curated lists of releases in
+// pkg/helm's manifest format; an operator adds a new addon or application
+// chart by editing the YAML, not these deploy functions.
+const (
+	eksAddonsManifest  = "configs/eks-addons.yaml"
+	helmChartsManifest = "configs/helm-charts.yaml"
+)
+
+func deployEKSAddons(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying EKS Addons microstack", nil)
+	// TODO: pass a pulumi.Provider pointed at the cluster deployEKS exports
+	// once that microstack is implemented; reconciled against the default
+	// provider in the meantime.
+	return helm.Reconcile(ctx, eksAddonsManifest, cfg.Customer, os.Getenv("AWS_REGION"))
+}
+
+func deployHelmCharts(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Helm Charts microstack", nil)
+	// TODO: pass a pulumi.Provider pointed at the cluster deployEKS exports
+	// once that microstack is implemented; reconciled against the default
+	// provider in the meantime.
+	return helm.Reconcile(ctx, helmChartsManifest, cfg.Customer, os.Getenv("AWS_REGION"))
+}
+
+func deployStorageClasses(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Storage Classes microstack", nil)
+	// TODO: Implement Kubernetes storage configurations
+	return nil
+}
+
+func deployIngressClasses(ctx *pulumi.Context, cfg *Config) error {
+	ctx.Log.Info("Deploying Ingress Classes microstack", nil)
+	// TODO: Implement ingress controllers and configurations
+	return nil
+}
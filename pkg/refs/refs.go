@@ -0,0 +1,100 @@
+// Package refs lets one microstack consume another microstack's outputs
+// through a typed pulumi.StackReference, instead of every deploy function
+// reaching for raw, stringly-typed stack outputs.
+package refs
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Stable export keys. A microstack's deploy function exports its outputs
+// under these keys; the Get* helpers below read them back by the same name.
+const (
+	NetworkingVpcIDKey            = "vpcId"
+	NetworkingPrivateSubnetIDsKey = "privateSubnetIds"
+	NetworkingPublicSubnetIDsKey  = "publicSubnetIds"
+
+	ACLsSecurityGroupIDsKey = "securityGroupIds"
+)
+
+// StackName formats the stable <customer>-<stage>-<microstack>-<region> name
+// every stack uses, so the CLI, the Automation API deployer, and the
+// StackReferences built here never drift apart.
+func StackName(customer, stage, microstack, region string) string {
+	return fmt.Sprintf("%s-%s-%s-%s", customer, stage, microstack, region)
+}
+
+// NetworkingOutputs are the typed outputs the networking microstack exports.
+type NetworkingOutputs struct {
+	VpcID            pulumi.StringOutput
+	PrivateSubnetIDs pulumi.StringArrayOutput
+	PublicSubnetIDs  pulumi.StringArrayOutput
+}
+
+// GetNetworking resolves the networking microstack's outputs for customer/region
+// via a StackReference.
+func GetNetworking(ctx *pulumi.Context, customer, region string) (*NetworkingOutputs, error) {
+	name := StackName(customer, "vpc", "networking", region)
+	ref, err := pulumi.NewStackReference(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve networking stack reference %s: %w", name, err)
+	}
+
+	return &NetworkingOutputs{
+		VpcID:            ref.GetStringOutput(pulumi.String(NetworkingVpcIDKey)),
+		PrivateSubnetIDs: toStringArrayOutput(ref.GetOutput(pulumi.String(NetworkingPrivateSubnetIDsKey))),
+		PublicSubnetIDs:  toStringArrayOutput(ref.GetOutput(pulumi.String(NetworkingPublicSubnetIDsKey))),
+	}, nil
+}
+
+// ACLsOutputs are the typed outputs the acls microstack exports.
+type ACLsOutputs struct {
+	SecurityGroupIDs pulumi.StringArrayOutput
+}
+
+// GetACLs resolves the acls microstack's outputs for customer/region via a
+// StackReference.
+func GetACLs(ctx *pulumi.Context, customer, region string) (*ACLsOutputs, error) {
+	name := StackName(customer, "vpc", "acls", region)
+	ref, err := pulumi.NewStackReference(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve acls stack reference %s: %w", name, err)
+	}
+
+	return &ACLsOutputs{
+		SecurityGroupIDs: toStringArrayOutput(ref.GetOutput(pulumi.String(ACLsSecurityGroupIDsKey))),
+	}, nil
+}
+
+// GetOutput resolves a single, arbitrary stack output by name from
+// stage/microstack's stack, for callers like pkg/helm's valuesFrom that
+// don't know the output's shape ahead of time and so can't use a typed
+// Get* helper above.
+func GetOutput(ctx *pulumi.Context, customer, stage, microstack, region, output string) (pulumi.AnyOutput, error) {
+	name := StackName(customer, stage, microstack, region)
+	ref, err := pulumi.NewStackReference(ctx, name, nil)
+	if err != nil {
+		return pulumi.AnyOutput{}, fmt.Errorf("failed to resolve %s stack reference %s: %w", microstack, name, err)
+	}
+	return ref.GetOutput(pulumi.String(output)), nil
+}
+
+// toStringArrayOutput adapts a raw untyped stack reference output into a
+// StringArrayOutput; StackReference has no typed getter for list outputs.
+func toStringArrayOutput(output pulumi.AnyOutput) pulumi.StringArrayOutput {
+	return output.ApplyT(func(v interface{}) []string {
+		raw, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		out := make([]string, 0, len(raw))
+		for _, item := range raw {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}).(pulumi.StringArrayOutput)
+}
@@ -0,0 +1,252 @@
+// Package statelock serializes deploys against a single stack with a
+// DynamoDB-backed lock, the way Terraform's S3 backend pairs a state bucket
+// with a lock table. Without it, two operators running `cmd-deploy` against
+// the same stack at once can corrupt Pulumi state.
+package statelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	lockIDAttr = "LockID"
+
+	// defaultTTL is used when Acquire is called with ttl <= 0, so a crashed
+	// run's lock eventually frees itself. Acquire heartbeats the lock well
+	// inside this window (see heartbeatFraction), so a live holder never
+	// loses the lock to its own TTL; defaultTTL only bounds how long a lock
+	// survives a holder that crashed or was killed without releasing it.
+	defaultTTL = 15 * time.Minute
+
+	// heartbeatFraction controls how often a held lock's ExpiresAt is
+	// renewed, expressed as a fraction of the TTL. A third of the TTL
+	// leaves two missed heartbeats of slack before the lock would expire
+	// out from under a holder that's merely slow to renew.
+	heartbeatFraction = 3
+)
+
+// TableName returns the DynamoDB lock table name for an environment/customer pair.
+func TableName(environment, customer string) string {
+	return fmt.Sprintf("pulumi-locks-%s-%s", environment, customer)
+}
+
+// CreateTable provisions the lock table, with server-side encryption and
+// point-in-time recovery enabled, if it doesn't already exist.
+func CreateTable(ctx context.Context, tableName, region string) error {
+	client, err := newClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}); err == nil {
+		fmt.Printf("DynamoDB lock table already exists: %s\n", tableName)
+		return nil
+	}
+
+	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(lockIDAttr), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(lockIDAttr), KeyType: types.KeyTypeHash},
+		},
+		BillingMode:      types.BillingModePayPerRequest,
+		SSESpecification: &types.SSESpecification{Enabled: aws.Bool(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create lock table: %w", err)
+	}
+
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, 2*time.Minute); err != nil {
+		return fmt.Errorf("timed out waiting for lock table to become active: %w", err)
+	}
+
+	_, err = client.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+		TableName: aws.String(tableName),
+		PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable point-in-time recovery: %w", err)
+	}
+
+	fmt.Printf("Created DynamoDB lock table: %s\n", tableName)
+	return nil
+}
+
+// Lock is a held lock on a stack, returned by Acquire and released with
+// Release. While held, Acquire's heartbeat goroutine keeps renewing
+// ExpiresAt so a long-running `up` doesn't outlive its own lock.
+type Lock struct {
+	client    *dynamodb.Client
+	table     string
+	stackName string
+	holder    string
+	ttl       time.Duration
+
+	stopHeartbeat chan struct{}
+	heartbeatDone chan struct{}
+}
+
+// Acquire takes an exclusive lock on stackName, recording the caller's STS
+// identity as its holder, failing if an unexpired lock already exists. ttl <= 0
+// uses defaultTTL. The returned Lock is kept alive by a background
+// heartbeat for as long as it's held; call Release when the operation
+// finishes to stop the heartbeat and free the stack immediately.
+func Acquire(ctx context.Context, tableName, stackName, region string, ttl time.Duration) (*Lock, error) {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	holder, err := callerIdentity(ctx, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := dynamodb.NewFromConfig(awsCfg)
+	now := time.Now().Unix()
+	expiresAt := time.Now().Add(ttl).Unix()
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]types.AttributeValue{
+			lockIDAttr:   &types.AttributeValueMemberS{Value: stackName},
+			"Holder":     &types.AttributeValueMemberS{Value: holder},
+			"AcquiredAt": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", now)},
+			"ExpiresAt":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expiresAt)},
+		},
+		ConditionExpression: aws.String(fmt.Sprintf("attribute_not_exists(%s) OR ExpiresAt < :now", lockIDAttr)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", now)},
+		},
+	})
+	if err != nil {
+		var condFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &condFailed) {
+			return nil, fmt.Errorf("stack %s is already locked (run `cmd-deploy unlock %s` if the holder crashed)", stackName, stackName)
+		}
+		return nil, fmt.Errorf("failed to acquire lock for %s: %w", stackName, err)
+	}
+
+	lock := &Lock{
+		client:        client,
+		table:         tableName,
+		stackName:     stackName,
+		holder:        holder,
+		ttl:           ttl,
+		stopHeartbeat: make(chan struct{}),
+		heartbeatDone: make(chan struct{}),
+	}
+	go lock.heartbeat()
+
+	return lock, nil
+}
+
+// heartbeat periodically renews ExpiresAt so the lock doesn't expire out
+// from under a still-running deploy. It stops as soon as Release is called.
+func (l *Lock) heartbeat() {
+	defer close(l.heartbeatDone)
+
+	ticker := time.NewTicker(l.ttl / heartbeatFraction)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stopHeartbeat:
+			return
+		case <-ticker.C:
+			if err := l.renew(context.Background()); err != nil {
+				fmt.Printf("Warning: failed to renew lock for %s: %v\n", l.stackName, err)
+			}
+		}
+	}
+}
+
+// renew extends ExpiresAt by ttl, as long as this Lock is still the holder
+// of record, so a crashed heartbeat can't resurrect a lock someone else
+// has since taken over (e.g. via `cmd-deploy unlock`).
+func (l *Lock) renew(ctx context.Context) error {
+	expiresAt := time.Now().Add(l.ttl).Unix()
+
+	_, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(l.table),
+		Key:                 map[string]types.AttributeValue{lockIDAttr: &types.AttributeValueMemberS{Value: l.stackName}},
+		UpdateExpression:    aws.String("SET ExpiresAt = :expiresAt"),
+		ConditionExpression: aws.String("Holder = :holder"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expiresAt": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expiresAt)},
+			":holder":    &types.AttributeValueMemberS{Value: l.holder},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew lock for %s: %w", l.stackName, err)
+	}
+	return nil
+}
+
+// Release stops the heartbeat and deletes the lock item, freeing the stack
+// for the next deploy.
+func (l *Lock) Release(ctx context.Context) error {
+	close(l.stopHeartbeat)
+	<-l.heartbeatDone
+
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key:       map[string]types.AttributeValue{lockIDAttr: &types.AttributeValueMemberS{Value: l.stackName}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release lock for %s: %w", l.stackName, err)
+	}
+	return nil
+}
+
+// Unlock force-removes a stack's lock item regardless of holder or TTL, for
+// the `cmd-deploy unlock` escape hatch on stuck locks.
+func Unlock(ctx context.Context, tableName, stackName, region string) error {
+	client, err := newClient(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       map[string]types.AttributeValue{lockIDAttr: &types.AttributeValueMemberS{Value: stackName}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", stackName, err)
+	}
+	return nil
+}
+
+func newClient(ctx context.Context, region string) (*dynamodb.Client, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return dynamodb.NewFromConfig(awsCfg), nil
+}
+
+func callerIdentity(ctx context.Context, awsCfg aws.Config) (string, error) {
+	out, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return aws.ToString(out.Arn), nil
+}